@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// avroLineCodec encodes parser.Line values as Avro binary records. The
+// schema is derived from the parsed file's field names the same way
+// newMessageFields derives an Elasticsearch mapping in es.go.
+type avroLineCodec struct {
+	codec      *goavro.Codec
+	fieldNames []string
+}
+
+func newAvroLineCodec(fieldNames []string) (*avroLineCodec, error) {
+	schema, err := avroLineSchema(fieldNames)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("building avro codec: %s", err)
+	}
+	return &avroLineCodec{codec: codec, fieldNames: fieldNames}, nil
+}
+
+func (c *avroLineCodec) Encode(l *parser.Line) ([]byte, error) {
+	record := make(map[string]interface{}, len(c.fieldNames))
+	for _, name := range c.fieldNames {
+		record[avroFieldName(name)] = avroUnion(avroFieldType(parser.GuessType(name)), l.Get(name))
+	}
+	_, buf, err := c.codec.NativeToBinary(record, nil)
+	return buf, err
+}
+
+func avroLineSchema(fieldNames []string) (string, error) {
+	var fields []string
+	for _, name := range fieldNames {
+		fields = append(fields, fmt.Sprintf(
+			`{"name": %q, "type": ["null", %q]}`,
+			avroFieldName(name), avroFieldType(parser.GuessType(name)),
+		))
+	}
+	schema := fmt.Sprintf(
+		`{"type": "record", "name": "AccessLogLine", "fields": [%s]}`,
+		strings.Join(fields, ", "),
+	)
+	return schema, nil
+}
+
+// avroFieldName maps a W3C field name (which may contain characters that
+// are not valid in an Avro field name, such as "cs(user-agent)") to a safe
+// identifier.
+func avroFieldName(name string) string {
+	replacer := strings.NewReplacer("(", "_", ")", "", "-", "_")
+	return replacer.Replace(name)
+}
+
+func avroFieldType(t parser.Kind) string {
+	switch t {
+	case parser.Float64:
+		return "double"
+	case parser.Int64:
+		return "long"
+	case parser.Bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func avroUnion(typ string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch typ {
+	case "double", "long", "boolean":
+		return map[string]interface{}{typ: value}
+	default:
+		return map[string]interface{}{"string": fmt.Sprintf("%v", value)}
+	}
+}
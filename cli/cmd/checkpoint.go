@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"os/signal"
+	"syscall"
+
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+	bolt "go.etcd.io/bbolt"
+)
+
+var resume bool
+var checkpointFile string
+
+var checkpointBucket = []byte("lines")
+
+// checkpointStore persists the number of lines successfully ingested from
+// each input file in a small BoltDB file, so a re-run with --resume skips
+// the already-ingested prefix of every file instead of replaying it. The
+// count is keyed on lines returned by the parser, not bytes read off the
+// underlying reader, since the parser may buffer ahead of whatever it has
+// actually delivered and a byte offset can overshoot real progress.
+type checkpointStore struct {
+	db *bolt.DB
+}
+
+func openCheckpointStore(path string) (*checkpointStore, error) {
+	db, err := bolt.Open(path, 0o644, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(checkpointBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &checkpointStore{db: db}, nil
+}
+
+// LineCount returns the number of lines already ingested from fname, as
+// recorded by the last SetLineCount call for that file.
+func (c *checkpointStore) LineCount(fname string) (count int64, found bool, err error) {
+	err = c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(checkpointBucket).Get([]byte(fname))
+		if v == nil {
+			return nil
+		}
+		count = int64(binary.BigEndian.Uint64(v))
+		found = true
+		return nil
+	})
+	return count, found, err
+}
+
+func (c *checkpointStore) SetLineCount(fname string, count int64) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, uint64(count))
+		return tx.Bucket(checkpointBucket).Put([]byte(fname), v)
+	})
+}
+
+func (c *checkpointStore) Close() error {
+	return c.db.Close()
+}
+
+// skipLines advances p past the first n lines, discarding them, and
+// returns how many it actually managed to skip (less than n if the file
+// is shorter or a parse error is hit). Resuming a checkpointed file works
+// by re-parsing its already-ingested prefix through the parser itself
+// rather than seeking the underlying reader to a raw byte offset, for the
+// same reason checkpoints are keyed on line count above: the parser may
+// buffer ahead of whatever it has actually delivered, so a byte offset can
+// overshoot real progress and silently drop lines.
+func skipLines(p *parser.FileParser, n int64) (int64, error) {
+	var skipped int64
+	for ; skipped < n; skipped++ {
+		l, err := p.Next()
+		if l == nil || err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+// cancelOnSignal returns a context that is cancelled as soon as the process
+// receives SIGINT or SIGTERM, so in-flight batches get a chance to finish
+// and connections can close cleanly instead of the process being killed
+// mid-upload.
+func cancelOnSignal() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+	return ctx, cancel
+}
@@ -11,14 +11,24 @@ import (
 )
 
 type esOpts struct {
-	S esSettings `json:"settings"`
-	M esMappings `json:"mappings"`
-}
-
-func newEsOpts(shards uint, replicas uint, checkStartup bool, refreshInterval time.Duration, fieldNames []string, excludes map[string]bool) esOpts {
+	S esSettings  `json:"settings"`
+	M interface{} `json:"mappings"`
+}
+
+// newEsOpts builds the index settings+mappings body. ES 7 dropped support
+// for more than one mapping type per index and ES 8 rejects the type key
+// entirely, so for typeless clusters the mapping properties are emitted
+// directly under "mappings" instead of nested under an "accesslogs" type.
+func newEsOpts(shards uint, replicas uint, checkStartup bool, refreshInterval time.Duration, fieldNames []string, excludes map[string]bool, typeless bool) esOpts {
+	var mappings interface{}
+	if typeless {
+		mappings = newTypelessMappings(fieldNames, excludes)
+	} else {
+		mappings = newMappings(fieldNames, excludes)
+	}
 	return esOpts{
 		S: newSettings(shards, replicas, checkStartup, refreshInterval),
-		M: newMappings(fieldNames, excludes),
+		M: mappings,
 	}
 }
 
@@ -58,6 +68,18 @@ type esType struct {
 	Properties esFields `json:"properties"`
 }
 
+// esTypelessMappings is the ES 7+/8+ mapping shape: properties live
+// directly under "mappings", with no intermediate document type.
+type esTypelessMappings struct {
+	Properties esFields `json:"properties"`
+}
+
+func newTypelessMappings(fieldNames []string, excludes map[string]bool) esTypelessMappings {
+	return esTypelessMappings{
+		Properties: newMessageFields(fieldNames, excludes),
+	}
+}
+
 type esFields map[string]anyEsField
 
 func newMessageFields(fieldNames []string, excludes map[string]bool) (fields esFields) {
@@ -264,3 +286,114 @@ type esLogger struct {
 func (l *esLogger) Printf(format string, v ...interface{}) {
 	l.Logger.Info(fmt.Sprintf(format, v...))
 }
+
+// esMajorVersion extracts the leading version number from the string
+// returned by client.ElasticsearchVersion, e.g. "7.17.0" -> 7.
+func esMajorVersion(version string) int {
+	major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return major
+}
+
+// ilmPolicy is the body of a PUT _ilm/policy/<name> request that rolls an
+// index over once it reaches maxSize or maxAge.
+type ilmPolicy struct {
+	Policy ilmPolicyPhases `json:"policy"`
+}
+
+type ilmPolicyPhases struct {
+	Phases ilmPhases `json:"phases"`
+}
+
+type ilmPhases struct {
+	Hot ilmHotPhase `json:"hot"`
+}
+
+type ilmHotPhase struct {
+	Actions ilmHotActions `json:"actions"`
+}
+
+type ilmHotActions struct {
+	Rollover ilmRollover `json:"rollover"`
+}
+
+type ilmRollover struct {
+	MaxSize string `json:"max_size,omitempty"`
+	MaxAge  string `json:"max_age,omitempty"`
+}
+
+func newILMPolicy(maxSize string, maxAge time.Duration) ilmPolicy {
+	rollover := ilmRollover{MaxSize: maxSize}
+	if maxAge > 0 {
+		rollover.MaxAge = strconv.FormatInt(int64(maxAge.Seconds()), 10) + "s"
+	}
+	return ilmPolicy{
+		Policy: ilmPolicyPhases{
+			Phases: ilmPhases{
+				Hot: ilmHotPhase{Actions: ilmHotActions{Rollover: rollover}},
+			},
+		},
+	}
+}
+
+// indexTemplate is the body of a PUT _template/<name> request that applies
+// settings+mappings to every new index matching the rollover alias pattern
+// and attaches the ILM policy named after the alias, so rollover keeps
+// pointing new writes at the alias's current write index.
+type indexTemplate struct {
+	IndexPatterns []string         `json:"index_patterns"`
+	Settings      templateSettings `json:"settings"`
+	Mappings      interface{}      `json:"mappings"`
+}
+
+type templateSettings struct {
+	esSettings
+	Lifecycle ilmIndexSettings `json:"lifecycle"`
+}
+
+type ilmIndexSettings struct {
+	Name          string `json:"name"`
+	RolloverAlias string `json:"rollover_alias"`
+}
+
+func newIndexTemplate(alias string, opts esOpts) indexTemplate {
+	return indexTemplate{
+		IndexPatterns: []string{alias + "-*"},
+		Settings: templateSettings{
+			esSettings: opts.S,
+			Lifecycle:  ilmIndexSettings{Name: alias, RolloverAlias: alias},
+		},
+		Mappings: opts.M,
+	}
+}
+
+// firstRolloverIndex is the name of the initial index backing a rollover
+// alias, e.g. "accesslogs-000001".
+func firstRolloverIndex(alias string) string {
+	return fmt.Sprintf("%s-000001", alias)
+}
+
+// dataStreamTemplate is the body of a PUT _index_template/<name> request
+// that registers an ES 8 data stream backed by a component template
+// carrying our settings+mappings.
+type dataStreamTemplate struct {
+	IndexPatterns []string         `json:"index_patterns"`
+	DataStream    dataStreamMarker `json:"data_stream"`
+	ComposedOf    []string         `json:"composed_of"`
+	Template      dataStreamInline `json:"template,omitempty"`
+}
+
+type dataStreamMarker struct{}
+
+type dataStreamInline struct {
+	Settings esSettings  `json:"settings"`
+	Mappings interface{} `json:"mappings"`
+}
+
+func newDataStreamTemplate(name string, opts esOpts) dataStreamTemplate {
+	return dataStreamTemplate{
+		IndexPatterns: []string{name},
+		DataStream:    dataStreamMarker{},
+		ComposedOf:    []string{name},
+		Template:      dataStreamInline{Settings: opts.S, Mappings: opts.M},
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+var sinkURIs []string
+var sinkChanSize int
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Parse accesslog files once and fan events out to multiple sinks",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fnames) == 0 {
+			fatal(errors.New("specify the files to be parsed"))
+		}
+		if len(sinkURIs) == 0 {
+			fatal(errors.New("specify at least one --sink URI"))
+		}
+
+		sinks := make([]Sink, 0, len(sinkURIs))
+		for _, raw := range sinkURIs {
+			sink, err := newSink(raw)
+			fatal(err)
+			sinks = append(sinks, sink)
+		}
+
+		// sinksOpened only flips to true once a fanOut call has actually
+		// succeeded in opening every sink: if the first file fails before
+		// or during sink.Open (a bad header, a sink that can't connect),
+		// sinks are left unopened and the next file must retry opening them
+		// rather than writing into their zero-value, not-yet-initialized
+		// state.
+		sinksOpened := false
+		for _, fname := range fnames {
+			fname = strings.TrimSpace(fname)
+			f, err := os.Open(fname)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
+				continue
+			}
+			err = fanOut(f, sinks, &sinksOpened)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error uploading '%s': %s\n", fname, err)
+				continue
+			}
+		}
+
+		for _, sink := range sinks {
+			sinkName := fmt.Sprintf("%T", sink)
+			err := writeWithRetry(sinkName, sink.Flush)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error flushing sink: %s\n", err)
+			}
+			if err := sink.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error closing sink: %s\n", err)
+			}
+		}
+	},
+}
+
+// newSink builds the Sink named by a URI such as "pg://...", "es://...",
+// "kafka://...", "otlp://...", "stdout://" or "parquet://...".
+func newSink(raw string) (Sink, error) {
+	uri, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URI '%s': %s", raw, err)
+	}
+	switch uri.Scheme {
+	case "pg", "postgres", "postgresql":
+		return newPgSink(uri)
+	case "es", "elasticsearch":
+		return newEsSink(uri)
+	case "kafka":
+		return newKafkaSink(uri)
+	case "otlp":
+		return newOtlpSink(uri)
+	case "parquet":
+		return newParquetSink(uri)
+	case "stdout":
+		return newStdoutSink(), nil
+	default:
+		return nil, fmt.Errorf("unknown sink scheme '%s'", uri.Scheme)
+	}
+}
+
+// fanOut parses f once and writes every line to every sink concurrently,
+// through one bounded channel per sink. Each sink's worker drains its own
+// channel; once a slow sink's channel is full, the parse loop blocks on it,
+// and so do the other sinks until it catches up.
+//
+// fanOut opens every sink only while *sinksOpened is false, and sets it to
+// true as soon as every Open has succeeded, whatever happens to the rest of
+// the file: Sink.Open is only meant to run once per sink (it allocates
+// connections, prepares batch state, etc.), so calling it again once sinks
+// are open would leak/deadlock stateful sinks like pgSink and discard any
+// buffered rows from the previous file.
+func fanOut(f *os.File, sinks []Sink, sinksOpened *bool) error {
+	p := parser.NewFileParser(f)
+	err := p.ParseHeader()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error building parser:", err)
+		return err
+	}
+	fieldNames := p.FieldNames()
+
+	if !*sinksOpened {
+		for _, sink := range sinks {
+			if err := sink.Open(fieldNames); err != nil {
+				return fmt.Errorf("opening sink: %s", err)
+			}
+		}
+		*sinksOpened = true
+	}
+
+	channels := make([]chan *parser.Line, len(sinks))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	recordErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	for i, sink := range sinks {
+		channels[i] = make(chan *parser.Line, sinkChanSize)
+		wg.Add(1)
+		go func(sink Sink, ch chan *parser.Line) {
+			defer wg.Done()
+			for l := range ch {
+				err := writeWithRetry(fmt.Sprintf("%T", sink), func() error {
+					return sink.Write(l)
+				})
+				if err != nil {
+					recordErr(err)
+				}
+			}
+		}(sink, channels[i])
+	}
+
+	var l *parser.Line
+	for {
+		l, err = p.Next()
+		if l == nil || err != nil {
+			break
+		}
+		for _, ch := range channels {
+			ch <- l
+		}
+	}
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
+	pushCmd.Flags().StringArrayVar(&sinkURIs, "sink", []string{}, "destination URI, can be repeated (pg://, es://, kafka://, otlp://, parquet://, stdout://)")
+	pushCmd.Flags().IntVar(&sinkChanSize, "sink-buffer", 1000, "number of buffered lines per sink before the parse loop blocks on it")
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/spf13/cobra"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+var chURI string
+var chTableName string
+var chBatchSize int
+var chAutoDDL bool
+
+var push2chCmd = &cobra.Command{
+	Use:   "push2ch",
+	Short: "Parse accesslog files and push events to ClickHouse",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fnames) == 0 {
+			fatal(errors.New("specify the files to be parsed"))
+		}
+		chURI = strings.TrimSpace(chURI)
+		if len(chURI) == 0 {
+			fatal(errors.New("Empty uri"))
+		}
+		if chBatchSize == 0 {
+			chBatchSize = 5000
+		}
+
+		opts, err := clickhouse.ParseDSN(chURI)
+		fatal(err)
+		conn, err := clickhouse.Open(opts)
+		fatal(err)
+		defer conn.Close()
+
+		for _, fname := range fnames {
+			fname = strings.TrimSpace(fname)
+			f, err := os.Open(fname)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
+				continue
+			}
+			err = uploadCH(f, conn, chBatchSize)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error uploading '%s': %s\n", fname, err)
+			}
+		}
+	},
+}
+
+// chKind maps a parser.Kind to the ClickHouse column type used to create
+// the destination table when --auto-ddl is set, analogous to how
+// newMessageFields builds the ES mapping in es.go.
+func chKind(name string, t parser.Kind) string {
+	switch t {
+	case parser.MyDate:
+		return "Date"
+	case parser.MyIP:
+		return "IPv6"
+	case parser.MyTime:
+		return "LowCardinality(String)"
+	case parser.MyTimestamp:
+		return "DateTime64(3)"
+	case parser.Float64:
+		return "Float64"
+	case parser.Int64:
+		return "Int64"
+	case parser.Bool:
+		return "UInt8"
+	default:
+		switch name {
+		case "cs-host", "cs-method", "sc-status":
+			return "LowCardinality(String)"
+		default:
+			return "String"
+		}
+	}
+}
+
+func chCreateTable(ctx context.Context, conn clickhouse.Conn, table string, fieldNames []string) error {
+	columns := make([]string, 0, len(fieldNames)+1)
+	columns = append(columns, "`@timestamp` DateTime64(3)")
+	for _, name := range fieldNames {
+		columns = append(columns, fmt.Sprintf("`%s` %s", name, chKind(name, parser.GuessType(name))))
+	}
+	ddl := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = MergeTree PARTITION BY toYYYYMM(toDate(`+"`@timestamp`"+`)) ORDER BY (toDate(`+"`@timestamp`"+`), `+"`cs-host`"+`)`,
+		table, strings.Join(columns, ", "),
+	)
+	return conn.Exec(ctx, ddl)
+}
+
+func uploadCH(f *os.File, conn clickhouse.Conn, bsize int) error {
+	ctx := context.Background()
+	p := parser.NewFileParser(f)
+	err := p.ParseHeader()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error building parser:", err)
+		return err
+	}
+	fieldNames := p.FieldNames()
+
+	if chAutoDDL {
+		err = chCreateTable(ctx, conn, chTableName, fieldNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	columnNames := append([]string{"@timestamp"}, fieldNames...)
+
+	flush := func(batch clickhouse.Batch) error {
+		return batch.Send()
+	}
+
+	var batch clickhouse.Batch
+	var nbInBatch int
+	var line *parser.Line
+
+	newBatch := func() (clickhouse.Batch, error) {
+		return conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (%s)", chTableName, strings.Join(quoteAll(columnNames), ", ")))
+	}
+
+	batch, err = newBatch()
+	if err != nil {
+		return err
+	}
+
+	var parseErr error
+	for {
+		line, parseErr = p.Next()
+		if line == nil || parseErr != nil {
+			break
+		}
+		values := make([]interface{}, 0, len(columnNames))
+		values = append(values, line.Get("@timestamp"))
+		for _, name := range fieldNames {
+			values = append(values, line.Get(name))
+		}
+		if err := batch.Append(values...); err != nil {
+			return err
+		}
+		nbInBatch++
+		if nbInBatch >= bsize {
+			if err := flush(batch); err != nil {
+				return err
+			}
+			batch, err = newBatch()
+			if err != nil {
+				return err
+			}
+			nbInBatch = 0
+		}
+	}
+
+	if nbInBatch > 0 {
+		if err := flush(batch); err != nil {
+			return err
+		}
+	}
+	return parseErr
+}
+
+func quoteAll(names []string) []string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("`%s`", name))
+	}
+	return quoted
+}
+
+func init() {
+	rootCmd.AddCommand(push2chCmd)
+	push2chCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
+	push2chCmd.Flags().StringVar(&chURI, "uri", "", "the ClickHouse connection DSN")
+	push2chCmd.Flags().StringVar(&chTableName, "tablename", "accesslogs", "name of ClickHouse table to push events to")
+	push2chCmd.Flags().IntVar(&chBatchSize, "batchsize", 5000, "batch size for ClickHouse inserts")
+	push2chCmd.Flags().BoolVar(&chAutoDDL, "auto-ddl", false, "create the destination table if it does not exist")
+}
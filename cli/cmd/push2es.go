@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/inconshreveable/log15"
 	"github.com/olivere/elastic"
@@ -14,6 +15,12 @@ import (
 	parser "github.com/stephane-martin/w3c-extendedlog-parser"
 )
 
+var ilmEnabled bool
+var ilmMaxSize string
+var ilmMaxAge time.Duration
+var rolloverAlias string
+var datastream bool
+
 var push2esCmd = &cobra.Command{
 	Use:   "push2es",
 	Short: "Parse accesslog files and push events to Elasticsearch",
@@ -42,8 +49,42 @@ var push2esCmd = &cobra.Command{
 		version, err := client.ElasticsearchVersion(esURL)
 		fatal(err)
 		fmt.Fprintln(os.Stdout, "Elasticsearch version:", version)
+		major := esMajorVersion(version)
+		typeless := major >= 7
+
+		ctx, cancel := cancelOnSignal()
+		defer cancel()
+
+		var checkpoints *checkpointStore
+		if resume || len(checkpointFile) > 0 {
+			if len(checkpointFile) == 0 {
+				fatal(errors.New("--checkpoint-file is required when --resume is set"))
+			}
+			checkpoints, err = openCheckpointStore(checkpointFile)
+			fatal(err)
+			defer checkpoints.Close()
+		}
 
-		ctx := context.Background()
+		// writeTarget is the name push2es indexes into: a plain index by
+		// default, a rollover alias when --ilm is set (named by
+		// --rollover-alias, or by --index when that is empty), or
+		// a data stream when --datastream is set (ES 8 only).
+		writeTarget := indexName
+		if datastream {
+			if major < 8 {
+				fatal(errors.New("--datastream requires Elasticsearch 8+"))
+			}
+			writeTarget = indexName
+			err = setupDataStream(ctx, client, indexName)
+			fatal(err)
+		} else if ilmEnabled {
+			if len(rolloverAlias) == 0 {
+				rolloverAlias = indexName
+			}
+			writeTarget = rolloverAlias
+			err = setupRollover(ctx, client, rolloverAlias, typeless)
+			fatal(err)
+		}
 
 		proc, err := client.BulkProcessor().
 			Name("push2esWorker").
@@ -55,41 +96,134 @@ var push2esCmd = &cobra.Command{
 		fatal(err)
 
 		for _, fname := range fnames {
-			fname = strings.TrimSpace(fname)
-			f, err := os.Open(fname)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
-				continue
+			if ctx.Err() != nil {
+				break
 			}
-			defer f.Close()
-
-			p := parser.NewFileParser(f)
-			err = p.ParseHeader()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "Error building parser:", err)
-				continue
-			}
-			var l *parser.Line
-			var i int
-			for {
-				l, err = p.Next()
-				if l == nil || err != nil {
-					break
-				}
-				i++
-				proc.Add(elastic.NewBulkIndexRequest().Doc(l).Index(indexName).Type("accesslogs"))
-				if i >= 1000 {
-					fatal(proc.Flush())
-					i = 0
-				}
-			}
-			if i > 0 {
-				fatal(proc.Flush())
+			if err := pushESFile(ctx, fname, proc, writeTarget, typeless, datastream, checkpoints); err != nil {
+				fmt.Fprintf(os.Stderr, "Error uploading '%s': %s\n", fname, err)
 			}
 		}
 	},
 }
 
+// pushESFile parses a single file and indexes its lines into writeTarget,
+// resuming from the last checkpointed line and persisting a new checkpoint
+// every 1000 lines and once more after the trailing partial batch.
+func pushESFile(ctx context.Context, fname string, proc *elastic.BulkProcessor, writeTarget string, typeless, datastream bool, checkpoints *checkpointStore) error {
+	fname = strings.TrimSpace(fname)
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var startLine int64
+	if checkpoints != nil && resume {
+		startLine, _, err = checkpoints.LineCount(fname)
+		if err != nil {
+			return err
+		}
+	}
+
+	p := parser.NewFileParser(f)
+	if err := p.ParseHeader(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error building parser:", err)
+		return err
+	}
+
+	curLine, err := skipLines(p, startLine)
+	if err != nil {
+		return fmt.Errorf("skipping already-ingested lines: %s", err)
+	}
+
+	var l *parser.Line
+	var i int
+	for {
+		if ctx.Err() != nil {
+			// cancelled: stop reading this file, but still fall
+			// through to the trailing flush/checkpoint below so
+			// the in-flight batch isn't dropped on SIGINT/SIGTERM
+			break
+		}
+		l, err = p.Next()
+		if l == nil || err != nil {
+			break
+		}
+		i++
+		curLine++
+		req := elastic.NewBulkIndexRequest().Doc(l).Index(writeTarget)
+		if !typeless {
+			req = req.Type("accesslogs")
+		}
+		if datastream {
+			req = req.OpType("create")
+		}
+		proc.Add(req)
+		if i >= 1000 {
+			fatal(proc.Flush())
+			i = 0
+			if checkpoints != nil {
+				fatal(checkpoints.SetLineCount(fname, curLine))
+			}
+		}
+	}
+	if i > 0 {
+		fatal(proc.Flush())
+		if checkpoints != nil {
+			fatal(checkpoints.SetLineCount(fname, curLine))
+		}
+	}
+	return err
+}
+
+// setupRollover creates the ILM policy, index template and first backing
+// index for a rollover alias, so long-running ingest can rotate indices by
+// size/age instead of growing a single index forever.
+func setupRollover(ctx context.Context, client *elastic.Client, alias string, typeless bool) error {
+	_, err := client.XPackIlmPutLifecycle().Policy(alias).BodyJson(newILMPolicy(ilmMaxSize, ilmMaxAge)).Do(ctx)
+	if err != nil {
+		return err
+	}
+	opts := newEsOpts(shards, replicas, checkStartup, refreshInterval, fieldNames, excludes, typeless)
+	_, err = client.IndexPutTemplate(alias).BodyJson(newIndexTemplate(alias, opts)).Do(ctx)
+	if err != nil {
+		return err
+	}
+	exists, err := client.IndexExists(firstRolloverIndex(alias)).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	_, err = client.CreateIndex(firstRolloverIndex(alias)).
+		BodyJson(map[string]interface{}{"aliases": map[string]interface{}{alias: map[string]interface{}{"is_write_index": true}}}).
+		Do(ctx)
+	return err
+}
+
+// setupDataStream registers a component template and an index template
+// backing a data stream, the ES 8 replacement for rollover aliases.
+func setupDataStream(ctx context.Context, client *elastic.Client, name string) error {
+	opts := newEsOpts(shards, replicas, checkStartup, refreshInterval, fieldNames, excludes, true)
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "PUT",
+		Path:   "/_component_template/" + name,
+		Body: map[string]interface{}{
+			"template": map[string]interface{}{"settings": opts.S, "mappings": opts.M},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "PUT",
+		Path:   "/_index_template/" + name,
+		Body:   newDataStreamTemplate(name, opts),
+	})
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(push2esCmd)
 	push2esCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
@@ -97,5 +231,11 @@ func init() {
 	push2esCmd.Flags().StringVar(&indexName, "index", "accesslogs", "Name of index to create")
 	push2esCmd.Flags().StringVar(&username, "username", "", "username for HTTP Basic Auth")
 	push2esCmd.Flags().StringVar(&password, "password", "", "password for HTTP Basic Auth")
-
+	push2esCmd.Flags().BoolVar(&ilmEnabled, "ilm", false, "create an ILM policy + rollover alias instead of indexing into a single fixed index")
+	push2esCmd.Flags().StringVar(&ilmMaxSize, "ilm-max-size", "50gb", "rollover once the write index reaches this size")
+	push2esCmd.Flags().DurationVar(&ilmMaxAge, "ilm-max-age", 0, "rollover once the write index reaches this age (0 disables the age condition)")
+	push2esCmd.Flags().StringVar(&rolloverAlias, "rollover-alias", "", "name of the rollover alias to create (defaults to --index)")
+	push2esCmd.Flags().BoolVar(&datastream, "datastream", false, "register a data stream + component template instead of a rollover alias (ES 8+)")
+	push2esCmd.Flags().BoolVar(&resume, "resume", false, "skip the already-ingested prefix of each file, as recorded in --checkpoint-file")
+	push2esCmd.Flags().StringVar(&checkpointFile, "checkpoint-file", "", "BoltDB file used to persist per-file ingested line counts")
 }
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/spf13/cobra"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+var kafkaBrokers []string
+var kafkaTopic string
+var kafkaKeyField string
+var kafkaFormat string
+var kafkaBatchSize int
+var kafkaTLSEnabled bool
+var kafkaSASLUsername string
+var kafkaSASLPassword string
+
+var push2kafkaCmd = &cobra.Command{
+	Use:   "push2kafka",
+	Short: "Parse accesslog files and push events to Kafka",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fnames) == 0 {
+			fatal(errors.New("specify the files to be parsed"))
+		}
+		if len(kafkaBrokers) == 0 {
+			fatal(errors.New("specify at least one kafka broker"))
+		}
+		kafkaTopic = strings.TrimSpace(kafkaTopic)
+		if len(kafkaTopic) == 0 {
+			fatal(errors.New("specify the kafka topic"))
+		}
+		if kafkaFormat != "json" && kafkaFormat != "avro" {
+			fatal(fmt.Errorf("unknown format '%s' (expected 'json' or 'avro')", kafkaFormat))
+		}
+		if kafkaBatchSize == 0 {
+			kafkaBatchSize = 1000
+		}
+
+		transport := &kafka.Transport{}
+		if kafkaTLSEnabled {
+			transport.TLS = &tls.Config{}
+		}
+		if len(kafkaSASLUsername) > 0 && len(kafkaSASLPassword) > 0 {
+			transport.SASL = plain.Mechanism{Username: kafkaSASLUsername, Password: kafkaSASLPassword}
+		}
+
+		writer := &kafka.Writer{
+			Addr:         kafka.TCP(kafkaBrokers...),
+			Topic:        kafkaTopic,
+			Balancer:     &kafka.Hash{},
+			BatchSize:    kafkaBatchSize,
+			RequiredAcks: kafka.RequireOne,
+			Transport:    transport,
+		}
+		defer writer.Close()
+
+		var encode func(*parser.Line) ([]byte, error)
+		var codec *avroLineCodec
+
+		for _, fname := range fnames {
+			fname = strings.TrimSpace(fname)
+			f, err := os.Open(fname)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
+				continue
+			}
+
+			p := parser.NewFileParser(f)
+			err = p.ParseHeader()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error building parser:", err)
+				f.Close()
+				continue
+			}
+
+			if encode == nil {
+				if kafkaFormat == "avro" {
+					codec, err = newAvroLineCodec(p.FieldNames())
+					fatal(err)
+					encode = codec.Encode
+				} else {
+					encode = jsonEncodeLine
+				}
+			}
+
+			err = pushKafka(p, writer, encode)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error uploading '%s': %s\n", fname, err)
+			}
+		}
+	},
+}
+
+// pushKafka parses all lines from p and writes them to the Kafka writer in
+// batches of kafkaBatchSize, keyed by the field named by kafkaKeyField when
+// it is set. kafka.Writer.WriteMessages blocks until the broker acks the
+// batch, which gives us back-pressure for free, mirroring the BulkProcessor
+// loop in push2es.go.
+func pushKafka(p *parser.FileParser, writer *kafka.Writer, encode func(*parser.Line) ([]byte, error)) error {
+	batch := make([]kafka.Message, 0, kafkaBatchSize)
+	var l *parser.Line
+	var err error
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := writer.WriteMessages(context.Background(), batch...)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		l, err = p.Next()
+		if l == nil || err != nil {
+			break
+		}
+		value, err := encode(l)
+		if err != nil {
+			return err
+		}
+		msg := kafka.Message{Value: value}
+		if len(kafkaKeyField) > 0 {
+			if key := l.Get(kafkaKeyField); key != nil {
+				msg.Key = []byte(fmt.Sprintf("%v", key))
+			}
+		}
+		batch = append(batch, msg)
+		if len(batch) >= kafkaBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return err
+}
+
+func jsonEncodeLine(l *parser.Line) ([]byte, error) {
+	return json.Marshal(l)
+}
+
+func init() {
+	rootCmd.AddCommand(push2kafkaCmd)
+	push2kafkaCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
+	push2kafkaCmd.Flags().StringArrayVar(&kafkaBrokers, "broker", []string{"127.0.0.1:9092"}, "kafka broker address (can be repeated)")
+	push2kafkaCmd.Flags().StringVar(&kafkaTopic, "topic", "", "kafka topic to push events to")
+	push2kafkaCmd.Flags().StringVar(&kafkaKeyField, "key-field", "c-ip", "W3C field used as the kafka partitioning key")
+	push2kafkaCmd.Flags().StringVar(&kafkaFormat, "format", "json", "message encoding: json or avro")
+	push2kafkaCmd.Flags().IntVar(&kafkaBatchSize, "batchsize", 1000, "number of messages per kafka produce batch")
+	push2kafkaCmd.Flags().BoolVar(&kafkaTLSEnabled, "tls", false, "enable TLS when connecting to the kafka brokers")
+	push2kafkaCmd.Flags().StringVar(&kafkaSASLUsername, "sasl-username", "", "username for SASL/PLAIN auth")
+	push2kafkaCmd.Flags().StringVar(&kafkaSASLPassword, "sasl-password", "", "password for SASL/PLAIN auth")
+}
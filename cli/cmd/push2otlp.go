@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlplogspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+var otlpEndpoint string
+var otlpProtocol string
+var otlpTLSEnabled bool
+var otlpHeaders []string
+var otlpGzip bool
+var otlpBatchSize int
+
+var push2otlpCmd = &cobra.Command{
+	Use:   "push2otlp",
+	Short: "Parse accesslog files and ship events as OpenTelemetry log records",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fnames) == 0 {
+			fatal(errors.New("specify the files to be parsed"))
+		}
+		otlpEndpoint = strings.TrimSpace(otlpEndpoint)
+		if len(otlpEndpoint) == 0 {
+			fatal(errors.New("specify the OTLP endpoint"))
+		}
+		if otlpProtocol != "grpc" && otlpProtocol != "http" {
+			fatal(fmt.Errorf("unknown protocol '%s' (expected 'grpc' or 'http')", otlpProtocol))
+		}
+		if otlpBatchSize == 0 {
+			otlpBatchSize = 1000
+		}
+
+		exporter, err := newOtlpExporter()
+		fatal(err)
+		defer exporter.Close()
+
+		for _, fname := range fnames {
+			fname = strings.TrimSpace(fname)
+			f, err := os.Open(fname)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
+				continue
+			}
+			err = pushOtlp(f, exporter)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error uploading '%s': %s\n", fname, err)
+			}
+		}
+	},
+}
+
+// otlpExporter ships batches of OTLP LogRecords to either the gRPC or the
+// HTTP OTLP/Logs endpoint, selected by --protocol.
+type otlpExporter struct {
+	conn       *grpc.ClientConn
+	grpcClient logspb.LogsServiceClient
+	httpClient *http.Client
+}
+
+func newOtlpExporter() (*otlpExporter, error) {
+	if otlpProtocol == "grpc" {
+		var creds credentials.TransportCredentials
+		if otlpTLSEnabled {
+			creds = credentials.NewTLS(nil)
+		} else {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.Dial(otlpEndpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, err
+		}
+		return &otlpExporter{conn: conn, grpcClient: logspb.NewLogsServiceClient(conn)}, nil
+	}
+	return &otlpExporter{httpClient: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (e *otlpExporter) Close() error {
+	if e.conn != nil {
+		return e.conn.Close()
+	}
+	return nil
+}
+
+func (e *otlpExporter) Export(records []*otlplogspb.LogRecord) error {
+	req := &logspb.ExportLogsServiceRequest{
+		ResourceLogs: []*otlplogspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						strAttr("service.name", "w3c-extendedlog-parser"),
+					},
+				},
+				ScopeLogs: []*otlplogspb.ScopeLogs{
+					{LogRecords: records},
+				},
+			},
+		},
+	}
+	if e.grpcClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		ctx = metadata.NewOutgoingContext(ctx, headersToMD(otlpHeaders))
+		_, err := e.grpcClient.Export(ctx, req)
+		return err
+	}
+	return e.exportHTTP(req)
+}
+
+// exportHTTP POSTs the request as protobuf to the OTLP/HTTP logs endpoint
+// (<endpoint>/v1/logs), gzip-compressing the body when --gzip is set.
+func (e *otlpExporter) exportHTTP(req *logspb.ExportLogsServiceRequest) error {
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var body *bytes.Buffer
+	if otlpGzip {
+		body = &bytes.Buffer{}
+		gz := gzip.NewWriter(body)
+		if _, err := gz.Write(payload); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	} else {
+		body = bytes.NewBuffer(payload)
+	}
+
+	scheme := "http"
+	if otlpTLSEnabled {
+		scheme = "https"
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s://%s/v1/logs", scheme, otlpEndpoint), body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	if otlpGzip {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for _, h := range otlpHeaders {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		httpReq.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("OTLP/HTTP export failed: %s: %s", resp.Status, respBody)
+	}
+	return nil
+}
+
+func pushOtlp(f *os.File, exporter *otlpExporter) error {
+	p := parser.NewFileParser(f)
+	err := p.ParseHeader()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error building parser:", err)
+		return err
+	}
+	fieldNames := p.FieldNames()
+
+	batch := make([]*otlplogspb.LogRecord, 0, otlpBatchSize)
+	var l *parser.Line
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := exporter.Export(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		l, err = p.Next()
+		if l == nil || err != nil {
+			break
+		}
+		batch = append(batch, lineToLogRecord(l, fieldNames))
+		if len(batch) >= otlpBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return err
+}
+
+// otlpSemConv maps well-known W3C field names to their OpenTelemetry
+// semantic convention attribute name. Fields with no known mapping are
+// preserved as-is so no data is silently dropped.
+var otlpSemConv = map[string]string{
+	"c-ip":           "client.address",
+	"cs-uri-path":    "url.path",
+	"cs-uri-query":   "url.query",
+	"cs-method":      "http.request.method",
+	"sc-status":      "http.response.status_code",
+	"cs(user-agent)": "user_agent.original",
+	"cs-host":        "url.domain",
+	"cs-bytes":       "http.request.body.size",
+	"sc-bytes":       "http.response.body.size",
+	"cs(referer)":    "http.request.header.referer",
+}
+
+func lineToLogRecord(l *parser.Line, fieldNames []string) *otlplogspb.LogRecord {
+	attrs := make([]*commonpb.KeyValue, 0, len(fieldNames))
+	for _, name := range fieldNames {
+		value := l.Get(name)
+		if value == nil {
+			continue
+		}
+		attrName, ok := otlpSemConv[name]
+		if !ok {
+			attrName = name
+		}
+		attrs = append(attrs, strAttr(attrName, fmt.Sprintf("%v", value)))
+	}
+
+	var timeUnixNano uint64
+	if ts, ok := l.Get("@timestamp").(time.Time); ok && !ts.IsZero() {
+		timeUnixNano = uint64(ts.UnixNano())
+	}
+
+	return &otlplogspb.LogRecord{
+		TimeUnixNano: timeUnixNano,
+		Attributes:   attrs,
+	}
+}
+
+func strAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func headersToMD(headers []string) metadata.MD {
+	md := metadata.MD{}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		md.Append(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return md
+}
+
+func init() {
+	rootCmd.AddCommand(push2otlpCmd)
+	push2otlpCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
+	push2otlpCmd.Flags().StringVar(&otlpEndpoint, "endpoint", "127.0.0.1:4317", "OTLP collector endpoint")
+	push2otlpCmd.Flags().StringVar(&otlpProtocol, "protocol", "grpc", "OTLP transport: grpc or http")
+	push2otlpCmd.Flags().BoolVar(&otlpTLSEnabled, "tls", false, "enable TLS when connecting to the OTLP endpoint")
+	push2otlpCmd.Flags().StringArrayVar(&otlpHeaders, "header", []string{}, "extra \"key: value\" header sent with every export request (can be repeated)")
+	push2otlpCmd.Flags().BoolVar(&otlpGzip, "gzip", false, "gzip-compress OTLP/HTTP request bodies")
+	push2otlpCmd.Flags().IntVar(&otlpBatchSize, "batchsize", 1000, "number of log records per OTLP export batch")
+}
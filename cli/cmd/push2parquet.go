@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+var parquetOutDir string
+var parquetCompression string
+var parquetRowGroupSize int64
+
+var push2parquetCmd = &cobra.Command{
+	Use:   "push2parquet",
+	Short: "Parse accesslog files and write Parquet files for cold storage",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fnames) == 0 {
+			fatal(errors.New("specify the files to be parsed"))
+		}
+		parquetOutDir = strings.TrimSpace(parquetOutDir)
+		if len(parquetOutDir) == 0 {
+			fatal(errors.New("specify --out-dir"))
+		}
+		if parquetRowGroupSize == 0 {
+			parquetRowGroupSize = 128 * 1024 * 1024
+		}
+
+		for _, fname := range fnames {
+			fname = strings.TrimSpace(fname)
+			f, err := os.Open(fname)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening '%s': %s\n", fname, err)
+				continue
+			}
+			err = uploadParquet(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error converting '%s': %s\n", fname, err)
+			}
+		}
+	},
+}
+
+// parquetKind maps a parser.Kind to the parquet-go SchemaElement tag used
+// to build the file's schema, analogous to how newMessageFields builds the
+// ES mapping from the same parser.GuessType calls.
+func parquetKind(t parser.Kind) string {
+	switch t {
+	case parser.Float64:
+		return "type=DOUBLE"
+	case parser.Int64:
+		return "type=INT64"
+	case parser.Bool:
+		return "type=BOOLEAN"
+	case parser.MyTimestamp:
+		return "type=INT64, convertedtype=TIMESTAMP_MILLIS"
+	default:
+		return "type=BYTE_ARRAY, convertedtype=UTF8"
+	}
+}
+
+// parquetFieldName sanitizes a W3C field name (e.g. "cs(user-agent)") into
+// a valid parquet/thrift identifier.
+func parquetFieldName(name string) string {
+	replacer := strings.NewReplacer("(", "_", ")", "", "-", "_")
+	return replacer.Replace(name)
+}
+
+// parquetSchema builds a JSON schema string for writer.NewJSONWriter from
+// the parsed file's field names. push2parquet builds a new writer set, and
+// so a new schema, for every input file, so a later file with extra
+// `#Fields:` columns than an earlier one gets its own wider Parquet files
+// instead of failing; files already written are left as they are.
+func parquetSchema(fieldNames []string) string {
+	tags := make([]string, 0, len(fieldNames))
+	for _, name := range fieldNames {
+		tags = append(tags, fmt.Sprintf(
+			`{"Tag": "name=%s, %s, repetitiontype=OPTIONAL"}`,
+			parquetFieldName(name), parquetKind(parser.GuessType(name)),
+		))
+	}
+	return fmt.Sprintf(`{"Tag": "name=line, repetitiontype=REQUIRED", "Fields": [%s]}`, strings.Join(tags, ", "))
+}
+
+// partitionPath builds the Hive-style "year=YYYY/month=MM/day=DD" prefix
+// for a line's timestamp so the output plugs directly into Athena/Trino/DuckDB
+// partition pruning.
+func partitionPath(ts time.Time) string {
+	return filepath.Join(
+		fmt.Sprintf("year=%04d", ts.Year()),
+		fmt.Sprintf("month=%02d", ts.Month()),
+		fmt.Sprintf("day=%02d", ts.Day()),
+	)
+}
+
+// parquetWriterSet fans parsed lines out to one Hive-partitioned Parquet
+// file per "year=YYYY/month=MM/day=DD" prefix, opening a new writer lazily
+// the first time a partition is seen. It is shared by push2parquet and
+// parquetSink so both write identically-partitioned output.
+type parquetWriterSet struct {
+	outDir       string
+	compression  string
+	rowGroupSize int64
+	schema       string
+	fieldNames   []string
+	writers      map[string]*writer.JSONWriter
+}
+
+func newParquetWriterSet(outDir, compression string, rowGroupSize int64, fieldNames []string) *parquetWriterSet {
+	return &parquetWriterSet{
+		outDir:       outDir,
+		compression:  compression,
+		rowGroupSize: rowGroupSize,
+		schema:       parquetSchema(fieldNames),
+		fieldNames:   fieldNames,
+		writers:      map[string]*writer.JSONWriter{},
+	}
+}
+
+func (s *parquetWriterSet) Write(l *parser.Line) error {
+	ts, _ := l.Get("@timestamp").(time.Time)
+	partition := partitionPath(ts)
+
+	w, ok := s.writers[partition]
+	if !ok {
+		outDir := filepath.Join(s.outDir, partition)
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return err
+		}
+		fw, err := local.NewLocalFileWriter(filepath.Join(outDir, fmt.Sprintf("%d.parquet", time.Now().UnixNano())))
+		if err != nil {
+			return err
+		}
+		w, err = writer.NewJSONWriter(s.schema, fw, 4)
+		if err != nil {
+			return err
+		}
+		w.RowGroupSize = s.rowGroupSize
+		w.CompressionType = parquetCodec(s.compression)
+		s.writers[partition] = w
+	}
+
+	record := make(map[string]interface{}, len(s.fieldNames))
+	for _, name := range s.fieldNames {
+		record[parquetFieldName(name)] = l.Get(name)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return w.Write(string(recordJSON))
+}
+
+func (s *parquetWriterSet) Close() error {
+	for _, w := range s.writers {
+		if err := w.WriteStop(); err != nil {
+			return err
+		}
+		if err := w.PFile.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uploadParquet(f *os.File) error {
+	p := parser.NewFileParser(f)
+	err := p.ParseHeader()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error building parser:", err)
+		return err
+	}
+	fieldNames := p.FieldNames()
+	writers := newParquetWriterSet(parquetOutDir, parquetCompression, parquetRowGroupSize, fieldNames)
+	defer writers.Close()
+
+	var l *parser.Line
+	for {
+		l, err = p.Next()
+		if l == nil || err != nil {
+			break
+		}
+		if err := writers.Write(l); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func parquetCodec(name string) parquet.CompressionCodec {
+	switch strings.ToLower(name) {
+	case "zstd":
+		return parquet.CompressionCodec_ZSTD
+	case "snappy", "":
+		return parquet.CompressionCodec_SNAPPY
+	case "none", "uncompressed":
+		return parquet.CompressionCodec_UNCOMPRESSED
+	default:
+		return parquet.CompressionCodec_SNAPPY
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(push2parquetCmd)
+	push2parquetCmd.Flags().StringArrayVar(&fnames, "filename", []string{}, "the files to parse")
+	push2parquetCmd.Flags().StringVar(&parquetOutDir, "out-dir", "", "root directory for Hive-partitioned Parquet output")
+	push2parquetCmd.Flags().StringVar(&parquetCompression, "compression", "snappy", "parquet compression codec: snappy, zstd or none")
+	push2parquetCmd.Flags().Int64Var(&parquetRowGroupSize, "rowgroup-size", 128*1024*1024, "target row group size in bytes")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -59,11 +60,25 @@ var push2pgCmd = &cobra.Command{
 		})
 		fatal(err)
 		defer pool.Close()
-		uploadFilesPG(filenames, pool, uint(parallel), batchsize)
+
+		ctx, cancel := cancelOnSignal()
+		defer cancel()
+
+		var checkpoints *checkpointStore
+		if resume || len(checkpointFile) > 0 {
+			if len(checkpointFile) == 0 {
+				fatal(errors.New("--checkpoint-file is required when --resume is set"))
+			}
+			checkpoints, err = openCheckpointStore(checkpointFile)
+			fatal(err)
+			defer checkpoints.Close()
+		}
+
+		uploadFilesPG(ctx, filenames, pool, uint(parallel), batchsize, checkpoints)
 	},
 }
 
-func uploadFilesPG(fnames []string, pool *pgx.ConnPool, nbInjectors uint, bsize int) {
+func uploadFilesPG(ctx context.Context, fnames []string, pool *pgx.ConnPool, nbInjectors uint, bsize int, checkpoints *checkpointStore) {
 	fnamesChan := make(chan string)
 	var wg sync.WaitGroup
 
@@ -76,19 +91,24 @@ func uploadFilesPG(fnames []string, pool *pgx.ConnPool, nbInjectors uint, bsize
 				if !ok {
 					return
 				}
-				uploadFilePG(fname, pool, bsize)
+				uploadFilePG(ctx, fname, pool, bsize, checkpoints)
 			}
 		}()
 	}
 
+FnameLoop:
 	for _, fname := range fnames {
-		fnamesChan <- fname
+		select {
+		case fnamesChan <- fname:
+		case <-ctx.Done():
+			break FnameLoop
+		}
 	}
 	close(fnamesChan)
 	wg.Wait()
 }
 
-func uploadFilePG(fname string, pool *pgx.ConnPool, bsize int) {
+func uploadFilePG(ctx context.Context, fname string, pool *pgx.ConnPool, bsize int, checkpoints *checkpointStore) {
 	fname = strings.TrimSpace(fname)
 	f, err := os.Open(fname)
 	if err != nil {
@@ -96,9 +116,19 @@ func uploadFilePG(fname string, pool *pgx.ConnPool, bsize int) {
 		return
 	}
 
+	var startLine int64
+	if checkpoints != nil && resume {
+		startLine, _, err = checkpoints.LineCount(fname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading checkpoint for '%s': %s\n", fname, err)
+			f.Close()
+			return
+		}
+	}
+
 	fmt.Fprintln(os.Stderr, "Uploading:", fname)
 	start := time.Now()
-	nbLines, err := uploadPG(f, pool, bsize)
+	nbLines, err := uploadPG(ctx, f, pool, bsize, fname, startLine, checkpoints)
 	duration := time.Now().Sub(start).Seconds()
 	f.Close()
 	if err == nil {
@@ -112,6 +142,16 @@ func uploadFilePG(fname string, pool *pgx.ConnPool, bsize int) {
 	}
 }
 
+// newPgRowID generates a fresh time-ordered UUID for the synthetic "id"
+// column every pg-bound table gets.
+func newPgRowID() ([]byte, error) {
+	id, err := uuid.NewV1()
+	if err != nil {
+		return nil, err
+	}
+	return id.Bytes(), nil
+}
+
 type Row []interface{}
 
 func (r *Row) AddField(field interface{}) error {
@@ -203,13 +243,19 @@ func (s *Source) Err() error {
 	return nil
 }
 
-func uploadPG(f io.Reader, connPool *pgx.ConnPool, bsize int) (nbLines int, err error) {
+func uploadPG(ctx context.Context, f io.Reader, connPool *pgx.ConnPool, bsize int, fname string, startLine int64, checkpoints *checkpointStore) (nbLines int, err error) {
 	p := parser.NewFileParser(f)
 	err = p.ParseHeader()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error building parser:", err)
 		return 0, err
 	}
+
+	curLine, err := skipLines(p, startLine)
+	if err != nil {
+		return 0, err
+	}
+
 	curFieldNames := p.FieldNames()
 	if !p.HasGmtTime() {
 		curFieldNames = append([]string{"gmttime"}, curFieldNames...)
@@ -247,6 +293,11 @@ func uploadPG(f io.Reader, connPool *pgx.ConnPool, bsize int) (nbLines int, err
 			return err
 		}
 		factory.Clear()
+		if checkpoints != nil {
+			if err := checkpoints.SetLineCount(fname, curLine); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
@@ -255,6 +306,12 @@ func uploadPG(f io.Reader, connPool *pgx.ConnPool, bsize int) (nbLines int, err
 	var line *parser.Line
 
 	for {
+		if ctx.Err() != nil {
+			// cancelled: flush what we have and stop cleanly instead of
+			// being killed mid-upload
+			break
+		}
+
 		line, err = p.NextTo(line)
 		if line == nil || err != nil {
 			break
@@ -269,15 +326,18 @@ func uploadPG(f io.Reader, connPool *pgx.ConnPool, bsize int) (nbLines int, err
 			}
 			row, _ = factory.GetRow()
 		}
+		// counted only after the flush above, so the checkpoint it writes
+		// covers just the lines already copied, not this one
+		curLine++
 
 		nbLines++
 		for _, name := range curFieldNames {
 			if name == "id" {
-				uuid, err := uuid.NewV1()
+				id, err := newPgRowID()
 				if err != nil {
 					return 0, err
 				}
-				err = row.AddField(uuid.Bytes())
+				err = row.AddField(id)
 				if err != nil {
 					return 0, err
 				}
@@ -404,4 +464,6 @@ func init() {
 	push2pgCmd.Flags().StringVar(&dbURI, "uri", "", "the URI of the postgresql server to connect to")
 	push2pgCmd.Flags().Uint8Var(&parallel, "parallel", 1, "number of parallel injectors")
 	push2pgCmd.Flags().IntVar(&batchsize, "batchsize", 5000, "batch size for postgresql INSERT")
+	push2pgCmd.Flags().BoolVar(&resume, "resume", false, "skip the already-ingested prefix of each file, as recorded in --checkpoint-file")
+	push2pgCmd.Flags().StringVar(&checkpointFile, "checkpoint-file", "", "BoltDB file used to persist per-file ingested line counts")
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// Sink is the common interface every push destination implements: pg, es,
+// kafka, otlp, stdout-json and parquet. The `push` command parses each
+// input file once and fans the resulting lines out to every configured
+// sink, instead of re-parsing the files once per destination command.
+type Sink interface {
+	// Open prepares the sink to receive lines for the given field names
+	// (e.g. creating a table, an index mapping, or an Avro schema).
+	Open(fieldNames []string) error
+	// Write buffers or sends a single parsed line.
+	Write(l *parser.Line) error
+	// Flush pushes any buffered lines to the destination.
+	Flush() error
+	// Close releases the sink's resources. Flush is not implied.
+	Close() error
+}
+
+// sinkRetryBackoff is the exponential backoff schedule used to retry a
+// failed Write/Flush against a sink, instead of the fail-fast
+// elastic.StopBackoff{} the push2es loop uses.
+func sinkRetryBackoff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 500 * time.Millisecond
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = 5 * time.Minute
+	return b
+}
+
+// writeWithRetry retries op with exponential backoff, logging each failed
+// attempt to stderr instead of aborting the whole run on the first error.
+func writeWithRetry(sinkName string, op func() error) error {
+	return backoff.RetryNotify(op, sinkRetryBackoff(), func(err error, next time.Duration) {
+		fmt.Fprintf(os.Stderr, "sink %s: %s, retrying in %s\n", sinkName, err, next)
+	})
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// esSink is the Sink adapter around the elastic.BulkProcessor push2es
+// uses directly.
+type esSink struct {
+	client     *elastic.Client
+	proc       *elastic.BulkProcessor
+	index      string
+	typeless   bool
+	batchSize  int
+	nbBuffered int
+}
+
+// newEsSink builds an esSink from an "es://host:port/index" URI.
+func newEsSink(uri *url.URL) (*esSink, error) {
+	scheme := "http"
+	if uri.Query().Get("tls") == "true" {
+		scheme = "https"
+	}
+	connURL := scheme + "://" + uri.Host
+
+	client, err := elastic.NewClient(elastic.SetURL(connURL))
+	if err != nil {
+		return nil, err
+	}
+	version, err := client.ElasticsearchVersion(connURL)
+	if err != nil {
+		return nil, err
+	}
+
+	index := strings.Trim(uri.Path, "/")
+	if len(index) == 0 {
+		index = "accesslogs"
+	}
+
+	return &esSink{client: client, index: index, typeless: esMajorVersion(version) >= 7, batchSize: 1000}, nil
+}
+
+func (s *esSink) Open(fieldNames []string) error {
+	// Retry failed bulk requests with exponential backoff instead of the
+	// elastic.StopBackoff{} push2es uses, so a transient ES hiccup doesn't
+	// abort the whole push run.
+	proc, err := s.client.BulkProcessor().
+		Name("pushSinkWorker-" + s.index).
+		BulkActions(-1).
+		BulkSize(-1).
+		Backoff(elastic.NewExponentialBackoff(500*time.Millisecond, 30*time.Second)).
+		Do(context.Background())
+	if err != nil {
+		return err
+	}
+	s.proc = proc
+	return nil
+}
+
+func (s *esSink) Write(l *parser.Line) error {
+	req := elastic.NewBulkIndexRequest().Doc(l).Index(s.index)
+	if !s.typeless {
+		req = req.Type("accesslogs")
+	}
+	s.proc.Add(req)
+	s.nbBuffered++
+	if s.nbBuffered >= s.batchSize {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *esSink) Flush() error {
+	if s.nbBuffered == 0 {
+		return nil
+	}
+	if err := s.proc.Flush(); err != nil {
+		// keep nbBuffered as-is: the batch is still sitting in the
+		// BulkProcessor and the caller can retry Flush
+		return err
+	}
+	s.nbBuffered = 0
+	return nil
+}
+
+func (s *esSink) Close() error {
+	return s.proc.Close()
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// kafkaSink is the Sink adapter around the same kafka.Writer push2kafka
+// uses directly.
+type kafkaSink struct {
+	writer    *kafka.Writer
+	keyField  string
+	format    string
+	batchSize int
+	batch     []kafka.Message
+	encode    func(*parser.Line) ([]byte, error)
+	codec     *avroLineCodec
+}
+
+// newKafkaSink builds a kafkaSink from a "kafka://broker1,broker2/topic?key=c-ip&format=json" URI.
+func newKafkaSink(uri *url.URL) (*kafkaSink, error) {
+	brokers := strings.Split(uri.Host, ",")
+	topic := strings.Trim(uri.Path, "/")
+	format := uri.Query().Get("format")
+	if len(format) == 0 {
+		format = "json"
+	}
+	keyField := uri.Query().Get("key")
+	if len(keyField) == 0 {
+		keyField = "c-ip"
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchSize:    kafkaBatchSize,
+		RequiredAcks: kafka.RequireOne,
+	}
+	return &kafkaSink{writer: writer, keyField: keyField, format: format, batchSize: 1000}, nil
+}
+
+func (s *kafkaSink) Open(fieldNames []string) error {
+	if s.format == "avro" {
+		codec, err := newAvroLineCodec(fieldNames)
+		if err != nil {
+			return err
+		}
+		s.codec = codec
+		s.encode = codec.Encode
+	} else {
+		s.encode = jsonEncodeLine
+	}
+	s.batch = make([]kafka.Message, 0, s.batchSize)
+	return nil
+}
+
+func (s *kafkaSink) Write(l *parser.Line) error {
+	value, err := s.encode(l)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{Value: value}
+	if key := l.Get(s.keyField); key != nil {
+		msg.Key = []byte(fmt.Sprintf("%v", key))
+	}
+	s.batch = append(s.batch, msg)
+	if len(s.batch) >= s.batchSize {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *kafkaSink) Flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	err := s.writer.WriteMessages(context.Background(), s.batch...)
+	s.batch = s.batch[:0]
+	return err
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"net/url"
+	"strconv"
+
+	otlplogspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// otlpSink is the Sink adapter around the otlpExporter push2otlp uses
+// directly.
+type otlpSink struct {
+	exporter   *otlpExporter
+	fieldNames []string
+	batchSize  int
+	batch      []*otlplogspb.LogRecord
+}
+
+// newOtlpSink builds an otlpSink from an "otlp://host:port?protocol=grpc&tls=true" URI.
+func newOtlpSink(uri *url.URL) (*otlpSink, error) {
+	otlpEndpoint = uri.Host
+	otlpProtocol = uri.Query().Get("protocol")
+	if len(otlpProtocol) == 0 {
+		otlpProtocol = "grpc"
+	}
+	otlpTLSEnabled, _ = strconv.ParseBool(uri.Query().Get("tls"))
+
+	exporter, err := newOtlpExporter()
+	if err != nil {
+		return nil, err
+	}
+	return &otlpSink{exporter: exporter, batchSize: 1000}, nil
+}
+
+func (s *otlpSink) Open(fieldNames []string) error {
+	s.fieldNames = fieldNames
+	s.batch = make([]*otlplogspb.LogRecord, 0, s.batchSize)
+	return nil
+}
+
+func (s *otlpSink) Write(l *parser.Line) error {
+	s.batch = append(s.batch, lineToLogRecord(l, s.fieldNames))
+	if len(s.batch) >= s.batchSize {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *otlpSink) Flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	err := s.exporter.Export(s.batch)
+	s.batch = s.batch[:0]
+	return err
+}
+
+func (s *otlpSink) Close() error {
+	return s.exporter.Close()
+}
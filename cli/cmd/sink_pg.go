@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/jackc/pgx"
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// pgSink is the Sink adapter around the batching logic uploadPG already
+// uses, so `push --sink pg://...` goes through the same Rows/CopyFrom path
+// as push2pg. Unlike uploadPG, it does not add a synthetic "gmttime"
+// column for files whose header has no GMT time field.
+type pgSink struct {
+	pool        *pgx.ConnPool
+	tableName   string
+	batchSize   int
+	columnNames []string
+	fieldNames  []string
+	types       map[string]parser.Kind
+	factory     *Rows
+	conn        *pgx.Conn
+}
+
+// newPgSink builds a pgSink from a "pg://user:pass@host:port/dbname?table=accesslogs&batchsize=5000" URI.
+func newPgSink(uri *url.URL) (*pgSink, error) {
+	connString := "postgres://" + uri.Host + uri.Path
+	if uri.User != nil {
+		connString = "postgres://" + uri.User.String() + "@" + uri.Host + uri.Path
+	}
+	config, err := pgx.ParseConnectionString(connString)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{ConnConfig: config, MaxConnections: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	table := uri.Query().Get("table")
+	if len(table) == 0 {
+		table = "accesslogs"
+	}
+	bsize := 5000
+	if raw := uri.Query().Get("batchsize"); len(raw) > 0 {
+		fmt.Sscanf(raw, "%d", &bsize)
+	}
+
+	return &pgSink{pool: pool, tableName: table, batchSize: bsize}, nil
+}
+
+func (s *pgSink) Open(fieldNames []string) error {
+	s.fieldNames = append([]string{"id"}, fieldNames...)
+	s.columnNames = make([]string, 0, len(s.fieldNames))
+	s.types = make(map[string]parser.Kind, len(s.fieldNames))
+	for _, name := range s.fieldNames {
+		s.columnNames = append(s.columnNames, pgKey(name))
+		s.types[name] = parser.GuessType(name)
+	}
+	s.factory = RowFactory(s.batchSize, len(s.fieldNames))
+
+	conn, err := s.pool.Acquire()
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *pgSink) Write(l *parser.Line) error {
+	row, full := s.factory.GetRow()
+	if full {
+		if err := s.Flush(); err != nil {
+			return err
+		}
+		row, _ = s.factory.GetRow()
+	}
+	for _, name := range s.fieldNames {
+		if name == "id" {
+			uuid, err := newPgRowID()
+			if err != nil {
+				return err
+			}
+			if err := row.AddField(uuid); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := row.AddField(pgConvert(s.types[name], l.Get(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pgSink) Flush() error {
+	if s.factory.Len() == 0 {
+		return nil
+	}
+	src, err := s.factory.GetSource()
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.CopyFrom(pgx.Identifier{s.tableName}, s.columnNames, src)
+	if err != nil {
+		return err
+	}
+	s.factory.Clear()
+	return nil
+}
+
+func (s *pgSink) Close() error {
+	if s.conn != nil {
+		s.pool.Release(s.conn)
+	}
+	s.pool.Close()
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	parser "github.com/stephane-martin/w3c-extendedlog-parser"
+)
+
+// stdoutSink writes one JSON-encoded line per parsed record to stdout. It
+// is the simplest Sink implementation and is handy for piping `push` into
+// another tool (jq, fluent, telegraf's exec input, ...).
+type stdoutSink struct {
+	w *bufio.Writer
+}
+
+func newStdoutSink() *stdoutSink {
+	return &stdoutSink{w: bufio.NewWriter(os.Stdout)}
+}
+
+func (s *stdoutSink) Open(fieldNames []string) error {
+	return nil
+}
+
+func (s *stdoutSink) Write(l *parser.Line) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	if err != nil {
+		return err
+	}
+	return s.w.WriteByte('\n')
+}
+
+func (s *stdoutSink) Flush() error {
+	return s.w.Flush()
+}
+
+func (s *stdoutSink) Close() error {
+	return s.w.Flush()
+}